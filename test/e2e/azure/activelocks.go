@@ -0,0 +1,180 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	activeLocksContainer = "e2e-active-locks"
+	activeLocksBlobName  = "active-locks.json"
+	// activeLocksMaxAttempts bounds the compare-and-swap retry loop in update() so two runs
+	// claiming/releasing at the same instant eventually converge instead of retrying forever.
+	activeLocksMaxAttempts = 10
+)
+
+// ActiveLocksTable tracks which resource group names are claimed by a currently-running e2e
+// run. It is backed by a blob in the same storage account soak tests already use
+// (acsesoaktests<location> in the acse-test-infrastructure-storage resource group), so the
+// Reaper can consult it before deleting anything and never pull a resource group out from
+// under a concurrent run.
+type ActiveLocksTable struct {
+	storageAccountName string
+}
+
+// NewActiveLocksTable returns the active-locks table for the soak storage account in location.
+func NewActiveLocksTable(location string) *ActiveLocksTable {
+	return &ActiveLocksTable{storageAccountName: "acsesoaktests" + location}
+}
+
+// activeLocksSnapshot is the table's content plus the ETag it was read at, so update() can
+// write back with an If-Match/If-None-Match condition instead of clobbering a concurrent
+// writer's claim.
+type activeLocksSnapshot struct {
+	names map[string]bool
+	etag  string // "" means the blob did not exist when this snapshot was read
+}
+
+// ActiveLocks returns the set of resource group names currently claimed by a live run. A
+// missing container or blob means no run has ever claimed a lock yet, and is treated as an
+// empty set rather than an error.
+func (t *ActiveLocksTable) ActiveLocks(ctx context.Context) (map[string]bool, error) {
+	snapshot, err := t.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.names, nil
+}
+
+// read fetches the current table content and the ETag it was read at.
+func (t *ActiveLocksTable) read(ctx context.Context) (activeLocksSnapshot, error) {
+	tmp, err := os.CreateTemp("", "active-locks-*.json")
+	if err != nil {
+		return activeLocksSnapshot{}, err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	out, err := exec.CommandContext(ctx, "az", "storage", "blob", "download",
+		"--account-name", t.storageAccountName,
+		"--container-name", activeLocksContainer,
+		"--name", activeLocksBlobName,
+		"--auth-mode", "login",
+		"--file", tmpPath,
+		"--query", "properties.etag",
+		"-o", "tsv",
+	).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "BlobNotFound") || strings.Contains(string(out), "ContainerNotFound") {
+			return activeLocksSnapshot{names: map[string]bool{}}, nil
+		}
+		return activeLocksSnapshot{}, fmt.Errorf("az storage blob download: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	content, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return activeLocksSnapshot{}, err
+	}
+	var names []string
+	if err := json.Unmarshal(content, &names); err != nil {
+		return activeLocksSnapshot{}, fmt.Errorf("parsing active-locks table: %s", err)
+	}
+	locks := make(map[string]bool, len(names))
+	for _, name := range names {
+		locks[name] = true
+	}
+	return activeLocksSnapshot{names: locks, etag: strings.TrimSpace(string(out))}, nil
+}
+
+// Claim adds name to the active-locks table so the Reaper leaves it alone for the duration of
+// the current run.
+func (t *ActiveLocksTable) Claim(ctx context.Context, name string) error {
+	return t.update(ctx, func(names map[string]bool) { names[name] = true })
+}
+
+// Release removes name from the active-locks table once the run that claimed it tears down.
+func (t *ActiveLocksTable) Release(ctx context.Context, name string) error {
+	return t.update(ctx, func(names map[string]bool) { delete(names, name) })
+}
+
+// update reads the table, applies mutate, and writes the result back conditioned on the ETag
+// it read (If-Match on an existing blob, If-None-Match "*" on a blob that didn't exist yet).
+// When a concurrent run wins the write, the conditional upload fails and update re-reads and
+// retries, so two runs claiming or releasing at the same time both land instead of one
+// silently dropping the other's change.
+func (t *ActiveLocksTable) update(ctx context.Context, mutate func(map[string]bool)) error {
+	var lastErr error
+	for attempt := 0; attempt < activeLocksMaxAttempts; attempt++ {
+		snapshot, err := t.read(ctx)
+		if err != nil {
+			return err
+		}
+		mutate(snapshot.names)
+
+		conflict, err := t.write(ctx, snapshot)
+		if err == nil {
+			return nil
+		}
+		if !conflict {
+			return err
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("active-locks table: giving up after %d conflicting writes: %s", activeLocksMaxAttempts, lastErr)
+}
+
+// write uploads snapshot.names back to the blob, conditioned on snapshot.etag. It returns
+// conflict=true when the upload failed because the blob changed since snapshot was read
+// (a precondition failure), so the caller knows to retry rather than surface the error.
+func (t *ActiveLocksTable) write(ctx context.Context, snapshot activeLocksSnapshot) (conflict bool, err error) {
+	names := make([]string, 0, len(snapshot.names))
+	for name := range snapshot.names {
+		names = append(names, name)
+	}
+	body, err := json.Marshal(names)
+	if err != nil {
+		return false, err
+	}
+
+	tmp, err := os.CreateTemp("", "active-locks-*.json")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	tmp.Close()
+
+	args := []string{"storage", "blob", "upload",
+		"--account-name", t.storageAccountName,
+		"--container-name", activeLocksContainer,
+		"--name", activeLocksBlobName,
+		"--file", tmp.Name(),
+		"--auth-mode", "login",
+		"--overwrite",
+	}
+	if snapshot.etag == "" {
+		args = append(args, "--if-none-match", "*")
+	} else {
+		args = append(args, "--if-match", snapshot.etag)
+	}
+
+	out, err := exec.CommandContext(ctx, "az", args...).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "ConditionNotMet") || strings.Contains(string(out), "PreconditionFailed") || strings.Contains(string(out), "BlobAlreadyExists") {
+			return true, fmt.Errorf("active-locks table changed concurrently: %s", strings.TrimSpace(string(out)))
+		}
+		return false, fmt.Errorf("az storage blob upload: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return false, nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-05-01/resources"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ReaperTagKey is the tag applied to every resource group created by an e2e run.
+	ReaperTagKey = "aks-engine-e2e"
+	// ReaperCreatedTagKey records when the resource group was created, in RFC3339.
+	ReaperCreatedTagKey = "created"
+	// ReaperHostnameTagKey records the CI host that created the resource group.
+	ReaperHostnameTagKey = "hostname"
+	// DefaultDeleteDanglingResourcesAfter is used when the config does not override the TTL.
+	DefaultDeleteDanglingResourcesAfter = 24 * time.Hour
+)
+
+// groupsClient is the subset of the generated resources.GroupsClient the Reaper depends on,
+// so tests can substitute a fake implementation instead of talking to ARM.
+type groupsClient interface {
+	ListComplete(ctx context.Context, filter string, top *int32) (resources.GroupListResultIterator, error)
+	Delete(ctx context.Context, resourceGroupName string) (resources.GroupsDeleteFuture, error)
+}
+
+// ReaperConfig controls which resource groups the Reaper considers dangling.
+type ReaperConfig struct {
+	// DeleteDanglingResourcesAfter is how old a tagged RG must be before it is eligible for deletion.
+	DeleteDanglingResourcesAfter time.Duration
+	// SoakClusterName is never reaped, even if it matches the tag and age criteria.
+	SoakClusterName string
+	// ActiveLocks returns the set of resource group names currently claimed by a live run
+	// (backed by the same storage account soak tests use for their lock table).
+	ActiveLocks func(ctx context.Context) (map[string]bool, error)
+}
+
+// Reaper deletes resource groups left behind by e2e runs that never tore themselves down,
+// e.g. because CleanUpIfFail was false or CI killed the job before teardown ran.
+type Reaper struct {
+	client groupsClient
+	cfg    ReaperConfig
+}
+
+// NewReaper builds a Reaper against the live ARM groups client for acct's subscription.
+func NewReaper(acct *Account, cfg ReaperConfig) (*Reaper, error) {
+	if cfg.DeleteDanglingResourcesAfter == 0 {
+		cfg.DeleteDanglingResourcesAfter = DefaultDeleteDanglingResourcesAfter
+	}
+	authorizer, err := auth.NewAuthorizerFromEnvironment()
+	if err != nil {
+		return nil, errors.Wrap(err, "building ARM authorizer for reaper")
+	}
+	client := resources.NewGroupsClient(acct.SubscriptionID)
+	client.Authorizer = authorizer
+	return &Reaper{client: client, cfg: cfg}, nil
+}
+
+// Run lists tagged resource groups and deletes every one that is past the TTL, isn't the
+// configured soak cluster, and isn't held by an active run's lock.
+func (r *Reaper) Run(ctx context.Context) error {
+	dangling, err := r.ListDanglingGroups(ctx)
+	if err != nil {
+		return errors.Wrap(err, "listing dangling resource groups")
+	}
+	if len(dangling) == 0 {
+		return nil
+	}
+
+	locks := map[string]bool{}
+	if r.cfg.ActiveLocks != nil {
+		locks, err = r.cfg.ActiveLocks(ctx)
+		if err != nil {
+			return errors.Wrap(err, "reading active-locks table")
+		}
+	}
+
+	var deleteErrs []string
+	for _, name := range dangling {
+		if name == r.cfg.SoakClusterName || locks[name] {
+			continue
+		}
+		if _, err := r.client.Delete(ctx, name); err != nil {
+			deleteErrs = append(deleteErrs, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+	}
+	if len(deleteErrs) > 0 {
+		return errors.Errorf("failed to reap %d resource group(s): %s", len(deleteErrs), strings.Join(deleteErrs, "; "))
+	}
+	return nil
+}
+
+// ListDanglingGroups returns the names of resource groups tagged by a previous e2e run whose
+// created timestamp is older than DeleteDanglingResourcesAfter. A group must carry both the
+// aks-engine-e2e tag and a parseable created tag to be considered; anything else is left alone.
+func (r *Reaper) ListDanglingGroups(ctx context.Context) ([]string, error) {
+	filter := fmt.Sprintf("tagName eq '%s'", ReaperTagKey)
+	it, err := r.client.ListComplete(ctx, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var dangling []string
+	cutoff := time.Now().Add(-r.cfg.DeleteDanglingResourcesAfter)
+	for ; it.NotDone(); err = it.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		g := it.Value()
+		if g.Name == nil || g.Tags == nil {
+			continue
+		}
+		createdTag, ok := g.Tags[ReaperCreatedTagKey]
+		if !ok || createdTag == nil {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, *createdTag)
+		if err != nil {
+			continue
+		}
+		if created.Before(cutoff) {
+			dangling = append(dangling, *g.Name)
+		}
+	}
+	return dangling, nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package azure
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-05-01/resources"
+)
+
+var liveAzure = flag.Bool("live-azure", false, "run reaper tests against a real Azure subscription")
+
+// fakeGroupsClient is an in-memory stand-in for the generated ARM client.
+type fakeGroupsClient struct {
+	groups  []resources.Group
+	deleted []string
+}
+
+func (f *fakeGroupsClient) ListComplete(ctx context.Context, filter string, top *int32) (resources.GroupListResultIterator, error) {
+	page := resources.GroupListResult{Value: &f.groups}
+	return resources.NewGroupListResultIterator(resources.NewGroupListResultPage(page, func(context.Context, resources.GroupListResult) (resources.GroupListResult, error) {
+		return resources.GroupListResult{}, nil
+	})), nil
+}
+
+func (f *fakeGroupsClient) Delete(ctx context.Context, resourceGroupName string) (resources.GroupsDeleteFuture, error) {
+	f.deleted = append(f.deleted, resourceGroupName)
+	return resources.GroupsDeleteFuture{}, nil
+}
+
+func taggedGroup(name string, created time.Time) resources.Group {
+	createdStr := created.Format(time.RFC3339)
+	return resources.Group{
+		Name: &name,
+		Tags: map[string]*string{
+			ReaperTagKey:        strPtr("pr-123"),
+			ReaperCreatedTagKey: &createdStr,
+		},
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestReaperDeletesOnlyStaleTaggedGroups(t *testing.T) {
+	client := &fakeGroupsClient{
+		groups: []resources.Group{
+			taggedGroup("stale-rg", time.Now().Add(-48*time.Hour)),
+			taggedGroup("fresh-rg", time.Now().Add(-1*time.Hour)),
+			{Name: strPtr("untagged-rg")},
+		},
+	}
+	r := &Reaper{client: client, cfg: ReaperConfig{DeleteDanglingResourcesAfter: 24 * time.Hour}}
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+
+	if len(client.deleted) != 1 || client.deleted[0] != "stale-rg" {
+		t.Fatalf("expected only stale-rg to be deleted, got %v", client.deleted)
+	}
+}
+
+func TestReaperSkipsSoakClusterAndActiveLocks(t *testing.T) {
+	client := &fakeGroupsClient{
+		groups: []resources.Group{
+			taggedGroup("soak-rg", time.Now().Add(-48*time.Hour)),
+			taggedGroup("locked-rg", time.Now().Add(-48*time.Hour)),
+		},
+	}
+	r := &Reaper{
+		client: client,
+		cfg: ReaperConfig{
+			DeleteDanglingResourcesAfter: 24 * time.Hour,
+			SoakClusterName:              "soak-rg",
+			ActiveLocks: func(ctx context.Context) (map[string]bool, error) {
+				return map[string]bool{"locked-rg": true}, nil
+			},
+		},
+	}
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+	if len(client.deleted) != 0 {
+		t.Fatalf("expected no deletions, got %v", client.deleted)
+	}
+}
+
+func TestReaperLiveAzure(t *testing.T) {
+	if !*liveAzure {
+		t.Skip("skipping live Azure reaper test; pass -live-azure to run against a real subscription")
+	}
+	acct, err := NewAccount()
+	if err != nil {
+		t.Fatalf("NewAccount: %s", err)
+	}
+	r, err := NewReaper(acct, ReaperConfig{DeleteDanglingResourcesAfter: DefaultDeleteDanglingResourcesAfter})
+	if err != nil {
+		t.Fatalf("NewReaper: %s", err)
+	}
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+}
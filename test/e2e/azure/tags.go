@@ -0,0 +1,49 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// StandardE2ETags builds the tag set every e2e-created resource group must carry so the Reaper
+// can find it later: aks-engine-e2e=<runID>, created=<RFC3339>, hostname=<ci-host>.
+func StandardE2ETags(runID, hostname string, created time.Time) map[string]string {
+	return map[string]string{
+		ReaperTagKey:         runID,
+		ReaperCreatedTagKey:  created.Format(time.RFC3339),
+		ReaperHostnameTagKey: hostname,
+	}
+}
+
+// TagResourceGroupWithRetry stamps tags onto an existing resource group via `az group update
+// --tags`, retrying like the other CLI-backed Account helpers so a transient `az` failure
+// doesn't leave a run's resource group untaggable (and therefore un-reapable).
+func TagResourceGroupWithRetry(ctx context.Context, name string, tags map[string]string, sleep, timeout time.Duration) error {
+	args := make([]string, 0, len(tags)+4)
+	args = append(args, "group", "update", "--name", name, "--tags")
+	for k, v := range tags {
+		args = append(args, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		out, err := exec.CommandContext(ctx, "az", args...).CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("az group update --tags: %s: %s", err, strings.TrimSpace(string(out)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
+	}
+	return lastErr
+}
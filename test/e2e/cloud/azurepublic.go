@@ -0,0 +1,72 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cloud
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/aks-engine/test/e2e/azure"
+)
+
+// azurePublicProvider backs Provider with the existing `az` CLI-driven azure.Account logic.
+// It is the default provider for Azure public cloud and Azure US Government, both of which
+// the CLI already targets reliably via `az cloud set`.
+type azurePublicProvider struct {
+	acct    *azure.Account
+	timeout time.Duration
+}
+
+func newAzurePublicProvider(acct *azure.Account, timeout time.Duration) *azurePublicProvider {
+	return &azurePublicProvider{acct: acct, timeout: timeout}
+}
+
+func (p *azurePublicProvider) Login(ctx context.Context) error {
+	return p.acct.LoginWithRetryContext(ctx, 3*time.Second, p.timeout)
+}
+
+func (p *azurePublicProvider) SetSubscription(ctx context.Context, subscriptionID string) error {
+	p.acct.SubscriptionID = subscriptionID
+	return p.acct.SetSubscriptionWithRetryContext(ctx, 3*time.Second, p.timeout)
+}
+
+// CreateResourceGroup ignores location: the group is set through
+// SetResourceGroupWithRetryContext, which takes only the name, and is then tagged.
+func (p *azurePublicProvider) CreateResourceGroup(ctx context.Context, name, location string, tags ResourceGroupTags) error {
+	if err := p.acct.SetResourceGroupWithRetryContext(ctx, name, 3*time.Second, p.timeout); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return azure.TagResourceGroupWithRetry(ctx, name, tags, 3*time.Second, p.timeout)
+}
+
+func (p *azurePublicProvider) DeleteResourceGroup(ctx context.Context, name string, wait bool) error {
+	return p.acct.DeleteGroupWithRetryContext(ctx, name, wait, 3*time.Second, p.timeout)
+}
+
+func (p *azurePublicProvider) StorageAccount(ctx context.Context, resourceGroup, name string) (StorageAccount, error) {
+	sa := p.acct.StorageAccount
+	sa.Name = name
+	sa.ResourceGroup.Name = resourceGroup
+	if err := sa.CreateStorageAccount(); err != nil {
+		return nil, err
+	}
+	if err := sa.SetConnectionString(); err != nil {
+		return nil, err
+	}
+	return &sa, nil
+}
+
+func (p *azurePublicProvider) ListDanglingGroups(ctx context.Context, after time.Duration) ([]string, error) {
+	r, err := azure.NewReaper(p.acct, azure.ReaperConfig{DeleteDanglingResourcesAfter: after})
+	if err != nil {
+		return nil, err
+	}
+	// ListDanglingGroups only reports candidates by tag/age; reapDanglingResourceGroups in
+	// runner.go is responsible for filtering out anything held in the active-locks table
+	// before actually deleting, since it (not this Provider) owns the deletion decision.
+	return r.ListDanglingGroups(ctx)
+}
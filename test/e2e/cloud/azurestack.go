@@ -0,0 +1,115 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/aks-engine/test/e2e/config"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-05-01/resources"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/pkg/errors"
+)
+
+// azureStackProvider talks to ARM directly via the custom Azure Stack endpoints in
+// config.CustomCloudConfig, instead of shelling out to `az`. The CLI's Azure Stack support is
+// flaky enough (clock skew, self-signed certs on some deployments, slower resource provider
+// registration) that driving ARM from the SDK is more reliable for e2e.
+type azureStackProvider struct {
+	groups         resources.GroupsClient
+	environment    azure.Environment
+	subscriptionID string
+}
+
+func newAzureStackProvider(cccfg *config.CustomCloudConfig) (*azureStackProvider, error) {
+	env, err := azure.EnvironmentFromURL(cccfg.Environment.ResourceManagerEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving Azure Stack environment from custom cloud config")
+	}
+	authorizer, err := auth.NewAuthorizerFromEnvironment()
+	if err != nil {
+		return nil, errors.Wrap(err, "building ARM authorizer for Azure Stack")
+	}
+	groups := resources.NewGroupsClientWithBaseURI(env.ResourceManagerEndpoint, cccfg.SubscriptionID)
+	groups.Authorizer = authorizer
+	return &azureStackProvider{
+		groups:         groups,
+		environment:    env,
+		subscriptionID: cccfg.SubscriptionID,
+	}, nil
+}
+
+func (p *azureStackProvider) Login(ctx context.Context) error {
+	// Authentication is handled by the autorest authorizer built in newAzureStackProvider;
+	// there's no separate login step when talking to ARM directly.
+	return nil
+}
+
+func (p *azureStackProvider) SetSubscription(ctx context.Context, subscriptionID string) error {
+	p.subscriptionID = subscriptionID
+	p.groups.SubscriptionID = subscriptionID
+	return nil
+}
+
+func (p *azureStackProvider) CreateResourceGroup(ctx context.Context, name, location string, tags ResourceGroupTags) error {
+	armTags := make(map[string]*string, len(tags))
+	for k, v := range tags {
+		v := v
+		armTags[k] = &v
+	}
+	_, err := p.groups.CreateOrUpdate(ctx, name, resources.Group{
+		Location: &location,
+		Tags:     armTags,
+	})
+	return err
+}
+
+func (p *azureStackProvider) DeleteResourceGroup(ctx context.Context, name string, wait bool) error {
+	future, err := p.groups.Delete(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !wait {
+		return nil
+	}
+	return future.WaitForCompletionRef(ctx, p.groups.Client)
+}
+
+func (p *azureStackProvider) StorageAccount(ctx context.Context, resourceGroup, name string) (StorageAccount, error) {
+	return nil, errors.New("azure stack storage account support is not implemented yet")
+}
+
+func (p *azureStackProvider) ListDanglingGroups(ctx context.Context, after time.Duration) ([]string, error) {
+	filter := fmt.Sprintf("tagName eq '%s'", "aks-engine-e2e")
+	it, err := p.groups.ListComplete(ctx, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-after)
+	var dangling []string
+	for ; it.NotDone(); err = it.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		g := it.Value()
+		if g.Name == nil || g.Tags == nil {
+			continue
+		}
+		createdTag, ok := g.Tags["created"]
+		if !ok || createdTag == nil {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, *createdTag)
+		if err != nil {
+			continue
+		}
+		if created.Before(cutoff) {
+			dangling = append(dangling, *g.Name)
+		}
+	}
+	return dangling, nil
+}
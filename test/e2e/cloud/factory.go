@@ -0,0 +1,19 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package cloud
+
+import (
+	"github.com/Azure/aks-engine/test/e2e/azure"
+	"github.com/Azure/aks-engine/test/e2e/config"
+)
+
+// NewProvider selects the Provider implementation for cfg: azurestack when cfg targets Azure
+// Stack, azurepublic otherwise (which also covers Azure US Government, since the `az` CLI
+// already handles that cloud reliably via `az cloud set`).
+func NewProvider(cfg *config.Config, cccfg *config.CustomCloudConfig, acct *azure.Account) (Provider, error) {
+	if cfg.IsAzureStackCloud() {
+		return newAzureStackProvider(cccfg)
+	}
+	return newAzurePublicProvider(acct, cfg.Timeout), nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// Package cloud abstracts the Azure operations the e2e harness needs behind a Provider
+// interface, so the same binary can drive Azure public cloud, Azure US Government (through the
+// same CLI-backed implementation as public cloud) and Azure Stack without main() special-casing
+// each one inline.
+package cloud
+
+import (
+	"context"
+	"time"
+)
+
+// ResourceGroupTags is the set of tags CreateResourceGroup stamps onto every group an e2e run
+// creates, so the reaper can find them later.
+type ResourceGroupTags map[string]string
+
+// Provider is the set of cloud operations the e2e harness depends on. Each supported cloud
+// gets its own implementation rather than branching inside the callers.
+type Provider interface {
+	// Login authenticates against the provider's cloud.
+	Login(ctx context.Context) error
+	// SetSubscription selects the subscription subsequent calls operate against.
+	SetSubscription(ctx context.Context, subscriptionID string) error
+	// CreateResourceGroup creates name in location, tagged with tags.
+	CreateResourceGroup(ctx context.Context, name, location string, tags ResourceGroupTags) error
+	// DeleteResourceGroup deletes name, optionally blocking until the deletion completes.
+	DeleteResourceGroup(ctx context.Context, name string, wait bool) error
+	// StorageAccount returns a handle to the named storage account in resourceGroup,
+	// creating it first if it does not already exist.
+	StorageAccount(ctx context.Context, resourceGroup, name string) (StorageAccount, error)
+	// ListDanglingGroups returns resource groups tagged by a previous e2e run whose creation
+	// time is older than after.
+	ListDanglingGroups(ctx context.Context, after time.Duration) ([]string, error)
+}
+
+// StorageAccount is the subset of storage operations the soak-test path and log bundler need.
+type StorageAccount interface {
+	UploadFiles(localDir, remoteShare string) error
+	DownloadFiles(remoteShare, localDir string) error
+	CreateFileShare(remoteShare string) error
+	DeleteFiles(remoteShare string) error
+}
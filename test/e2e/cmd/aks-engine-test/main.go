@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+// Command aks-engine-test hosts e2e maintenance utilities that don't belong in the main
+// provisioning binary's flag set, starting with the dangling resource group reaper.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Azure/aks-engine/test/e2e/azure"
+	"github.com/Azure/aks-engine/test/e2e/config"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: aks-engine-test <reap> [flags]")
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "reap":
+		runReap(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown subcommand %q\n", os.Args[1])
+		os.Exit(1)
+	}
+}
+
+func runReap(args []string) {
+	fs := flag.NewFlagSet("reap", flag.ExitOnError)
+	ttl := fs.Duration("ttl", azure.DefaultDeleteDanglingResourcesAfter, "age after which a tagged resource group is considered dangling")
+	soakClusterName := fs.String("soak-cluster-name", "", "resource group name to never reap")
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("Error parsing reap flags: %s\n", err)
+	}
+
+	cfg, err := config.ParseConfig()
+	if err != nil {
+		log.Fatalf("Error while trying to parse configuration: %s\n", err)
+	}
+
+	acct, err := azure.NewAccount()
+	if err != nil {
+		log.Fatalf("Error while trying to setup azure account: %s\n", err)
+	}
+	if err := acct.LoginWithRetry(3*time.Second, cfg.Timeout); err != nil {
+		log.Fatalf("Error while trying to login to azure account: %s\n", err)
+	}
+	if err := acct.SetSubscriptionWithRetry(3*time.Second, cfg.Timeout); err != nil {
+		log.Fatalf("Error while trying to set azure subscription: %s\n", err)
+	}
+
+	locks := azure.NewActiveLocksTable(cfg.Location)
+	r, err := azure.NewReaper(acct, azure.ReaperConfig{
+		DeleteDanglingResourcesAfter: *ttl,
+		SoakClusterName:              *soakClusterName,
+		ActiveLocks:                  locks.ActiveLocks,
+	})
+	if err != nil {
+		log.Fatalf("Error while trying to build reaper: %s\n", err)
+	}
+	if err := r.Run(context.Background()); err != nil {
+		log.Fatalf("Error while reaping dangling resource groups: %s\n", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import "github.com/Azure/aks-engine/pkg/api"
+
+// HasAddon reports whether the cluster definition enables the named addon, either via the
+// legacy Addons list or the newer Components list.
+func (e *Engine) HasAddon(name string) bool {
+	if e == nil || e.ClusterDefinition == nil || e.ClusterDefinition.Properties == nil {
+		return false
+	}
+	k := e.ClusterDefinition.Properties.OrchestratorProfile.KubernetesConfig
+	if k == nil {
+		return false
+	}
+	for _, addon := range k.Addons {
+		if addon.Name == name {
+			return addon.IsEnabled()
+		}
+	}
+	for _, component := range k.Components {
+		if component.Name == name {
+			return component.IsEnabled()
+		}
+	}
+	return false
+}
+
+// HasDashboard reports whether the kubernetes-dashboard addon is enabled.
+func (e *Engine) HasDashboard() bool { return e.HasAddon("kubernetes-dashboard") }
+
+// HasTiller reports whether the tiller addon is enabled.
+func (e *Engine) HasTiller() bool { return e.HasAddon("tiller") }
+
+// HasACIConnector reports whether the aci-connector addon is enabled.
+func (e *Engine) HasACIConnector() bool { return e.HasAddon("aci-connector") }
+
+// HasKeyvaultFlexvolume reports whether the keyvault-flexvolume addon is enabled.
+func (e *Engine) HasKeyvaultFlexvolume() bool { return e.HasAddon("keyvault-flexvolume") }
+
+// HasAzurePolicy reports whether the azure-policy addon is enabled.
+func (e *Engine) HasAzurePolicy() bool { return e.HasAddon("azure-policy") }
+
+// HasAzureDiskCSI reports whether the azuredisk-csi-driver addon is enabled.
+func (e *Engine) HasAzureDiskCSI() bool { return e.HasAddon("azuredisk-csi-driver") }
+
+// HasAzureFileCSI reports whether the azurefile-csi-driver addon is enabled.
+func (e *Engine) HasAzureFileCSI() bool { return e.HasAddon("azurefile-csi-driver") }
+
+// HasCalico reports whether the cluster uses the calico network policy.
+func (e *Engine) HasCalico() bool {
+	if e == nil || e.ClusterDefinition == nil || e.ClusterDefinition.Properties == nil {
+		return false
+	}
+	k := e.ClusterDefinition.Properties.OrchestratorProfile.KubernetesConfig
+	if k == nil {
+		return false
+	}
+	return k.NetworkPolicy == "calico"
+}
+
+// HasWindowsAgents reports whether any agent pool runs Windows.
+func (e *Engine) HasWindowsAgents() bool {
+	return e.anyAgentPool(func(p *api.AgentPoolProfile) bool { return p.OSType == api.Windows })
+}
+
+// HasLinuxAgents reports whether any agent pool runs Linux.
+func (e *Engine) HasLinuxAgents() bool {
+	return e.anyAgentPool(func(p *api.AgentPoolProfile) bool { return p.OSType != api.Windows })
+}
+
+// HasGPUNode reports whether any agent pool uses a GPU-capable VM size.
+func (e *Engine) HasGPUNode() bool {
+	return e.anyAgentPool(func(p *api.AgentPoolProfile) bool { return api.IsNvidiaEnabledSKU(p.VMSize) })
+}
+
+// HasAvailabilityZones reports whether any agent pool is spread across availability zones.
+func (e *Engine) HasAvailabilityZones() bool {
+	return e.anyAgentPool(func(p *api.AgentPoolProfile) bool { return len(p.AvailabilityZones) > 0 })
+}
+
+// HasManagedIdentity reports whether the cluster uses a managed identity rather than a
+// service principal.
+func (e *Engine) HasManagedIdentity() bool {
+	if e == nil || e.ClusterDefinition == nil || e.ClusterDefinition.Properties == nil {
+		return false
+	}
+	k := e.ClusterDefinition.Properties.OrchestratorProfile.KubernetesConfig
+	if k == nil {
+		return false
+	}
+	return k.UseManagedIdentity
+}
+
+func (e *Engine) anyAgentPool(pred func(*api.AgentPoolProfile) bool) bool {
+	if e == nil || e.ClusterDefinition == nil || e.ClusterDefinition.Properties == nil {
+		return false
+	}
+	for _, p := range e.ClusterDefinition.Properties.AgentPoolProfiles {
+		if pred(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+)
+
+func engineWithAddon(name string, enabled bool) *Engine {
+	return &Engine{
+		ClusterDefinition: &api.ContainerService{
+			Properties: &api.Properties{
+				OrchestratorProfile: &api.OrchestratorProfile{
+					KubernetesConfig: &api.KubernetesConfig{
+						Addons: []api.KubernetesAddon{
+							{Name: name, Enabled: &enabled},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestHasAddon(t *testing.T) {
+	e := engineWithAddon("tiller", true)
+	if !e.HasAddon("tiller") {
+		t.Error("expected HasAddon(tiller) to be true")
+	}
+	if e.HasAddon("dashboard") {
+		t.Error("expected HasAddon(dashboard) to be false for an absent addon")
+	}
+	if !e.HasTiller() {
+		t.Error("expected HasTiller() to be true")
+	}
+}
+
+func TestHasAddonDisabled(t *testing.T) {
+	e := engineWithAddon("tiller", false)
+	if e.HasAddon("tiller") {
+		t.Error("expected HasAddon(tiller) to be false when explicitly disabled")
+	}
+}
+
+func TestHasAddonNilEngine(t *testing.T) {
+	var e *Engine
+	if e.HasAddon("tiller") {
+		t.Error("expected HasAddon on a nil Engine to be false, not panic")
+	}
+}
+
+func engineWithNilKubernetesConfig() *Engine {
+	return &Engine{
+		ClusterDefinition: &api.ContainerService{
+			Properties: &api.Properties{
+				OrchestratorProfile: &api.OrchestratorProfile{},
+			},
+		},
+	}
+}
+
+func TestHasCalicoNilKubernetesConfig(t *testing.T) {
+	e := engineWithNilKubernetesConfig()
+	if e.HasCalico() {
+		t.Error("expected HasCalico to be false, not panic, with a nil KubernetesConfig")
+	}
+}
+
+func TestHasCalico(t *testing.T) {
+	e := &Engine{
+		ClusterDefinition: &api.ContainerService{
+			Properties: &api.Properties{
+				OrchestratorProfile: &api.OrchestratorProfile{
+					KubernetesConfig: &api.KubernetesConfig{NetworkPolicy: "calico"},
+				},
+			},
+		},
+	}
+	if !e.HasCalico() {
+		t.Error("expected HasCalico to be true when NetworkPolicy is calico")
+	}
+}
+
+func TestHasManagedIdentityNilKubernetesConfig(t *testing.T) {
+	e := engineWithNilKubernetesConfig()
+	if e.HasManagedIdentity() {
+		t.Error("expected HasManagedIdentity to be false, not panic, with a nil KubernetesConfig")
+	}
+}
+
+func TestHasManagedIdentity(t *testing.T) {
+	e := &Engine{
+		ClusterDefinition: &api.ContainerService{
+			Properties: &api.Properties{
+				OrchestratorProfile: &api.OrchestratorProfile{
+					KubernetesConfig: &api.KubernetesConfig{UseManagedIdentity: true},
+				},
+			},
+		},
+	}
+	if !e.HasManagedIdentity() {
+		t.Error("expected HasManagedIdentity to be true when UseManagedIdentity is set")
+	}
+}
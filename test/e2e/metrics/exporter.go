@@ -0,0 +1,151 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Exporter sends a Point (and its recorded phase timings) to a metrics backend. ExportAll fans
+// out to every configured Exporter so a single run can feed a local file, a fleet-wide
+// Pushgateway and a JSON webhook at the same time. There is no OTLP exporter.
+type Exporter interface {
+	Export(ctx context.Context, p *Point) error
+}
+
+// ExportAll fires every exporter concurrently and returns the combined errors, if any. One
+// slow or unreachable sink (e.g. a Pushgateway behind a VPN) should never hold up the others.
+func ExportAll(ctx context.Context, p *Point, exporters []Exporter) []error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, e := range exporters {
+		e := e
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.Export(ctx, p); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// FileExporter is the original behavior: write the point as a JSON blob to disk. It stays
+// around as one of possibly several configured sinks.
+type FileExporter struct{}
+
+// Export writes p to disk, the same line-protocol/JSON blob Write() always produced.
+func (e *FileExporter) Export(ctx context.Context, p *Point) error {
+	return p.Write()
+}
+
+// PushgatewayExporter pushes p's timings to a Prometheus Pushgateway under
+// job=aks-engine-e2e, labeled by orchestrator/location/cluster-definition/subscription.
+type PushgatewayExporter struct {
+	URL    string
+	Client *http.Client
+}
+
+// Export PUTs p's phase timings to the Pushgateway as Prometheus text format. The run's total
+// time is not pushed.
+func (e *PushgatewayExporter) Export(ctx context.Context, p *Point) error {
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var buf bytes.Buffer
+	labels := p.pushgatewayLabels()
+	for phase, d := range p.PhaseTimings() {
+		fmt.Fprintf(&buf, "aks_engine_e2e_phase_seconds{phase=%q,%s} %f\n", phase, labels, d.Seconds())
+	}
+
+	url := fmt.Sprintf("%s/metrics/job/aks-engine-e2e", e.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, &buf)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway returned status %s", resp.Status)
+	}
+	return nil
+}
+
+// WebhookExporter POSTs a simple ad hoc JSON timing payload to Endpoint. It is not an OTLP/HTTP
+// collector client — there's no protobuf encoding, no /v1/metrics path, and no OTLP
+// resource/metric schema — just a webhook any HTTP endpoint that can accept this JSON shape can
+// consume (a small collector-side adapter, a Slack-style incoming webhook, etc.).
+type WebhookExporter struct {
+	Endpoint string
+	Client   *http.Client
+}
+
+type webhookPayload struct {
+	Orchestrator string             `json:"orchestrator"`
+	Location     string             `json:"location"`
+	Phases       map[string]float64 `json:"phases"`
+	RecordedAt   string             `json:"recordedAt"`
+}
+
+// Export POSTs the JSON timing payload to e.Endpoint.
+func (e *WebhookExporter) Export(ctx context.Context, p *Point) error {
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	phases := map[string]float64{}
+	for name, d := range p.PhaseTimings() {
+		phases[name] = d.Seconds()
+	}
+	payload := webhookPayload{
+		Orchestrator: p.Orchestrator,
+		Location:     p.Location,
+		Phases:       phases,
+		RecordedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %s", resp.Status)
+	}
+	return nil
+}
+
+func (p *Point) pushgatewayLabels() string {
+	return fmt.Sprintf(
+		`orchestrator=%q,location=%q,cluster_definition=%q,subscription=%q`,
+		p.Orchestrator, p.Location, p.ClusterDefinition, p.SubscriptionID,
+	)
+}
@@ -0,0 +1,74 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPushgatewayExporterExport(t *testing.T) {
+	var gotMethod, gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := BuildPoint("kubernetes", "eastus", "default", "sub-id")
+	p.StartSpan("nodes_ready").End()
+
+	e := &PushgatewayExporter{URL: srv.URL}
+	if err := e.Export(context.Background(), p); err != nil {
+		t.Fatalf("Export: %s", err)
+	}
+	if gotMethod != http.MethodPut {
+		t.Fatalf("expected PUT, got %s", gotMethod)
+	}
+	if gotPath != "/metrics/job/aks-engine-e2e" {
+		t.Fatalf("expected job path, got %s", gotPath)
+	}
+}
+
+func TestPushgatewayExporterNonSuccessStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	p := BuildPoint("kubernetes", "eastus", "default", "sub-id")
+	e := &PushgatewayExporter{URL: srv.URL}
+	if err := e.Export(context.Background(), p); err == nil {
+		t.Fatal("expected an error for a non-2xx pushgateway response")
+	}
+}
+
+func TestWebhookExporterExport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected json content type, got %s", r.Header.Get("Content-Type"))
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	p := BuildPoint("kubernetes", "eastus", "default", "sub-id")
+	e := &WebhookExporter{Endpoint: srv.URL}
+	if err := e.Export(context.Background(), p); err != nil {
+		t.Fatalf("Export: %s", err)
+	}
+}
+
+func TestExportAllCollectsErrorsFromFailingSinks(t *testing.T) {
+	p := BuildPoint("kubernetes", "eastus", "default", "sub-id")
+	errs := ExportAll(context.Background(), p, []Exporter{
+		&WebhookExporter{Endpoint: "http://127.0.0.1:0"},
+	})
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error from an unreachable endpoint, got %d", len(errs))
+	}
+}
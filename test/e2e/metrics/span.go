@@ -0,0 +1,59 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	phaseMu      sync.Mutex
+	phaseTimings = map[*Point]map[string]time.Duration{}
+)
+
+// Span times a single named phase of a run (e.g. provision_deploy, ssh_ready) against the
+// Point it was started from. Call End() when the phase finishes.
+type Span struct {
+	point *Point
+	name  string
+	start time.Time
+}
+
+// StartSpan begins timing a phase of the run. The common phases are provision_generate,
+// provision_deploy, ssh_ready, nodes_ready, and ginkgo_suite, but any name is accepted.
+func (p *Point) StartSpan(name string) *Span {
+	return &Span{point: p, name: name, start: time.Now()}
+}
+
+// End records the elapsed time since StartSpan against the originating Point.
+func (s *Span) End() {
+	if s == nil || s.point == nil {
+		return
+	}
+	s.point.recordPhase(s.name, time.Since(s.start))
+}
+
+func (p *Point) recordPhase(name string, d time.Duration) {
+	phaseMu.Lock()
+	defer phaseMu.Unlock()
+	timings, ok := phaseTimings[p]
+	if !ok {
+		timings = map[string]time.Duration{}
+		phaseTimings[p] = timings
+	}
+	timings[name] = d
+}
+
+// PhaseTimings returns a copy of the phase durations recorded against p so far.
+func (p *Point) PhaseTimings() map[string]time.Duration {
+	phaseMu.Lock()
+	defer phaseMu.Unlock()
+	timings := phaseTimings[p]
+	out := make(map[string]time.Duration, len(timings))
+	for k, v := range timings {
+		out[k] = v
+	}
+	return out
+}
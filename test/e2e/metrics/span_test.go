@@ -0,0 +1,31 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpanRecordsPhaseTiming(t *testing.T) {
+	p := BuildPoint("kubernetes", "eastus", "default", "sub-id")
+
+	span := p.StartSpan("provision_deploy")
+	time.Sleep(5 * time.Millisecond)
+	span.End()
+
+	timings := p.PhaseTimings()
+	d, ok := timings["provision_deploy"]
+	if !ok {
+		t.Fatal("expected provision_deploy to be recorded")
+	}
+	if d <= 0 {
+		t.Fatalf("expected a positive duration, got %s", d)
+	}
+}
+
+func TestSpanEndOnNilSpanDoesNotPanic(t *testing.T) {
+	var s *Span
+	s.End()
+}
@@ -4,20 +4,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Azure/aks-engine/test/e2e/azure"
+	"github.com/Azure/aks-engine/test/e2e/cloud"
 	"github.com/Azure/aks-engine/test/e2e/config"
 	"github.com/Azure/aks-engine/test/e2e/engine"
 	"github.com/Azure/aks-engine/test/e2e/metrics"
 	"github.com/Azure/aks-engine/test/e2e/runner"
 )
 
+// defaultShutdownGrace bounds how long teardown is given to finish once a shutdown signal
+// arrives, after which main exits regardless of whether cleanup completed.
+const defaultShutdownGrace = 2 * time.Minute
+
 var (
 	cfg            *config.Config
 	cccfg          *config.CustomCloudConfig
@@ -27,9 +35,13 @@ var (
 	err            error
 	pt             *metrics.Point
 	cliProvisioner *runner.CLIProvisioner
+	teardownOnce   sync.Once
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	cwd, _ := os.Getwd()
 	cfg, err = config.ParseConfig()
 
@@ -59,43 +71,67 @@ func main() {
 		log.Fatalf("Error while trying to setup azure account: %s\n", err)
 	}
 
-	err := acct.LoginWithRetry(3*time.Second, cfg.Timeout)
+	// Built immediately after the account so login, subscription selection, soak storage and
+	// reaping all go through the same Provider. The soak cluster's resource-group lookup and
+	// deletion, and the deletions in teardown, still call acct directly.
+	cloudProvider, err := cloud.NewProvider(cfg, cccfg, acct)
+	if err != nil {
+		log.Fatalf("Error while trying to build cloud provider for %s\n", err)
+	}
+
+	err = cloudProvider.Login(ctx)
 	if err != nil {
 		log.Fatalf("Error while trying to login to azure account! %s\n", err)
 	}
 
-	err = acct.SetSubscriptionWithRetry(3*time.Second, cfg.Timeout)
+	err = cloudProvider.SetSubscription(ctx, acct.SubscriptionID)
 	if err != nil {
 		log.Fatal("Error while trying to set azure subscription!")
 	}
 	pt = metrics.BuildPoint(cfg.Orchestrator, cfg.Location, cfg.ClusterDefinition, acct.SubscriptionID)
 
-	// If an interrupt/kill signal is sent we will run the clean up procedure
-	trap()
+	reapDanglingResourceGroups(ctx, cloudProvider)
+
+	// Once ctx is cancelled (signal received, or main returns) run teardown exactly once and
+	// give it defaultShutdownGrace to finish before forcing the process down. teardown runs
+	// against a fresh context, not ctx itself: ctx is already Done() by the time we get here,
+	// and every context-aware step inside doTeardown (metrics export, RG deletion, log upload)
+	// needs its own time budget rather than inheriting an already-cancelled one.
+	go func() {
+		<-ctx.Done()
+		log.Printf("Received shutdown signal, cleaning up (grace period %s) ...\n", defaultShutdownGrace)
+		sctx, cancel := shutdownContext()
+		defer cancel()
+		done := make(chan struct{})
+		go func() {
+			teardown(sctx)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-sctx.Done():
+			log.Printf("Teardown did not finish within the grace period, exiting anyway\n")
+		}
+		os.Exit(1)
+	}()
 
-	cliProvisioner, err = runner.BuildCLIProvisioner(cfg, acct, pt)
+	cliProvisioner, err = runner.BuildCLIProvisioner(ctx, cfg, acct, pt)
 	if err != nil {
 		log.Fatalf("Error while trying to build CLI Provisioner:%s", err)
 	}
 
-	sa := acct.StorageAccount
-
-	// Soak test specific setup
+	// Soak test specific setup. The soak storage account is fetched through cloudProvider
+	// (which creates it and sets up its connection string) rather than driving
+	// acct.StorageAccount directly, same as login/subscription above.
+	var storage cloud.StorageAccount
 	if cfg.SoakClusterName != "" {
-		sa.Name = "acsesoaktests" + cfg.Location
-		sa.ResourceGroup.Name = "acse-test-infrastructure-storage"
-		sa.ResourceGroup.Location = cfg.Location
-		err = sa.CreateStorageAccount()
-		if err != nil {
-			log.Fatalf("Error while trying to create storage account: %s\n", err)
-		}
-		err = sa.SetConnectionString()
+		storage, err = cloudProvider.StorageAccount(ctx, "acse-test-infrastructure-storage", "acsesoaktests"+cfg.Location)
 		if err != nil {
-			log.Fatalf("Error while trying to set storage account connection string: %s\n", err)
+			log.Fatalf("Error while trying to set up soak storage account: %s\n", err)
 		}
 		provision := true
 		rg := cfg.SoakClusterName
-		err = acct.SetResourceGroupWithRetry(rg, 3*time.Second, cfg.Timeout)
+		err = acct.SetResourceGroupWithRetryContext(ctx, rg, 3*time.Second, cfg.Timeout)
 		if err != nil {
 			log.Printf("Error while trying to set RG:%s\n", err)
 		} else {
@@ -110,19 +146,19 @@ func main() {
 		if provision || cfg.ForceDeploy {
 			log.Printf("Soak cluster %s does not exist or has expired\n", rg)
 			log.Printf("Deleting Resource Group:%s\n", rg)
-			acct.DeleteGroupWithRetry(rg, true, 3*time.Second, cfg.Timeout)
+			acct.DeleteGroupWithRetryContext(ctx, rg, true, 3*time.Second, cfg.Timeout)
 			log.Printf("Deleting Storage files:%s\n", rg)
-			sa.DeleteFiles(cfg.SoakClusterName)
+			storage.DeleteFiles(cfg.SoakClusterName)
 			cfg.Name = ""
 		} else {
 			log.Printf("Soak cluster %s exists, downloading output files from storage...\n", rg)
-			err = sa.DownloadFiles(cfg.SoakClusterName, "_output")
+			err = storage.DownloadFiles(cfg.SoakClusterName, "_output")
 			if err != nil {
 				log.Printf("Error while trying to download _output dir: %s, will provision a new cluster.\n", err)
 				log.Printf("Deleting Resource Group:%s\n", rg)
-				acct.DeleteGroupWithRetry(rg, true, 3*time.Second, cfg.Timeout)
+				acct.DeleteGroupWithRetryContext(ctx, rg, true, 3*time.Second, cfg.Timeout)
 				log.Printf("Deleting Storage files:%s\n", rg)
-				sa.DeleteFiles(cfg.SoakClusterName)
+				storage.DeleteFiles(cfg.SoakClusterName)
 				cfg.Name = ""
 			} else {
 				cfg.SetSSHKeyPermissions()
@@ -131,44 +167,43 @@ func main() {
 	}
 	// Only provision a cluster if there isn't a name present
 	if cfg.Name == "" {
-		err = cliProvisioner.Run()
+		// cliProvisioner.Run covers template generation, ARM deployment, and waiting for SSH and
+		// nodes ready, but CLIProvisioner's implementation isn't part of this package, so we can
+		// only see the whole call as one opaque step from out here. provision_deploy below spans
+		// that whole call; provision_generate, ssh_ready, and nodes_ready are NOT separately
+		// instrumented — that would require splitting them out inside Run itself.
+		provisionSpan := pt.StartSpan("provision_deploy")
+		err = cliProvisioner.Run(ctx)
+		provisionSpan.End()
 		rgs = cliProvisioner.ResourceGroups
 		eng = cliProvisioner.Engine
+		// Tag every resource group this run touched, success or failure, so the Reaper can
+		// identify and clean it up later even if this run never reaches a clean teardown.
+		tagCreatedResourceGroups(ctx, cloudProvider, rgs)
 		if err != nil {
-			if cfg.CleanUpIfFail {
-				teardown()
-			}
-			log.Fatalf("Error while trying to provision cluster:%s", err)
-			os.Exit(1)
+			failAndExit("Error while trying to provision cluster:%s", err)
 		}
 		if cfg.SoakClusterName != "" {
-			err = sa.CreateFileShare(cfg.SoakClusterName)
+			err = storage.CreateFileShare(cfg.SoakClusterName)
 			if err != nil {
 				log.Printf("Error while trying to create file share:%s\n", err)
 			}
-			err = sa.UploadFiles(filepath.Join(cfg.CurrentWorkingDir, "_output"), cfg.SoakClusterName)
+			err = storage.UploadFiles(filepath.Join(cfg.CurrentWorkingDir, "_output"), cfg.SoakClusterName)
 			if err != nil {
 				log.Fatalf("Error while trying to upload _output dir:%s\n", err)
 			}
 		}
 	} else {
 		rgs = append(rgs, cliProvisioner.Config.Name)
+		tagCreatedResourceGroups(ctx, cloudProvider, rgs)
 		engCfg, err := engine.ParseConfig(cfg.CurrentWorkingDir, cfg.ClusterDefinition, cfg.Name)
 		cfg.SetKubeConfig()
 		if err != nil {
-			if cfg.CleanUpIfFail {
-				teardown()
-			}
-			log.Fatalf("Error trying to parse Engine config:%s\n", err)
-			os.Exit(1)
+			failAndExit("Error trying to parse Engine config:%s\n", err)
 		}
 		cs, err := engine.ParseInput(engCfg.ClusterDefinitionTemplate)
 		if err != nil {
-			if cfg.CleanUpIfFail {
-				teardown()
-			}
-			log.Fatalf("Error trying to parse engine template into memory:%s\n", err)
-			os.Exit(1)
+			failAndExit("Error trying to parse engine template into memory:%s\n", err)
 		}
 		eng = &engine.Engine{
 			Config:            engCfg,
@@ -178,44 +213,176 @@ func main() {
 	}
 
 	if !cfg.SkipTest {
-		g, err := runner.BuildGinkgoRunner(cfg, pt)
+		// Translate the capability matrix into --skip/--focus so specs requiring an addon the
+		// current cluster definition doesn't have (see RegisterSpec/UnsupportedSpecs) are
+		// skipped instead of failing outright.
+		skip, focus := runner.GinkgoSkipFocusArgs(eng)
+		g, err := runner.BuildGinkgoRunner(cfg, pt, skip, focus)
 		if err != nil {
-			if cfg.CleanUpIfFail {
-				teardown()
-			}
-			log.Fatalf("Error: Unable to parse ginkgo configuration!")
-			os.Exit(1)
+			failAndExit("Error: Unable to parse ginkgo configuration!")
 		}
-		err = g.Run()
+		suiteSpan := pt.StartSpan("ginkgo_suite")
+		err = g.Run(ctx)
+		suiteSpan.End()
 		if err != nil {
 			if cfg.CleanUpIfFail {
-				teardown()
+				gracefulTeardownAndExit(1)
 			}
 			os.Exit(1)
 		}
 	}
 
-	teardown()
-	os.Exit(0)
+	gracefulTeardownAndExit(0)
 }
 
-func trap() {
-	// If an interrupt/kill signal is sent we will run the clean up procedure
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	signal.Notify(c, os.Kill)
-	go func() {
-		for sig := range c {
-			log.Printf("Received Signal:%s ... Clean Up On Exit?:%v\n", sig.String(), cfg.CleanUpOnExit)
-			teardown()
-			os.Exit(1)
+// shutdownContext returns a fresh context bounded by defaultShutdownGrace, decoupled from the
+// (possibly already-cancelled) signal context, so every context-aware step inside doTeardown
+// gets its own grace period instead of returning instantly.
+func shutdownContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), defaultShutdownGrace)
+}
+
+// gracefulTeardownAndExit runs teardown against a fresh grace-bounded context and exits with
+// code. teardownOnce means it's safe to call this from multiple racing call sites (a failed
+// step in main, the signal-watching goroutine): whichever gets there first does the real work.
+func gracefulTeardownAndExit(code int) {
+	sctx, cancel := shutdownContext()
+	defer cancel()
+	teardown(sctx)
+	os.Exit(code)
+}
+
+// failAndExit logs a fatal error and exits 1, running teardown first when cfg.CleanUpIfFail is
+// set. It replaces the old log.Fatalf+conditional-teardown pattern, which could race an
+// in-flight signal-driven shutdown and skip cleanup if that goroutine's os.Exit won first.
+func failAndExit(format string, args ...interface{}) {
+	log.Printf(format, args...)
+	if cfg.CleanUpIfFail {
+		gracefulTeardownAndExit(1)
+	}
+	os.Exit(1)
+}
+
+// reapDanglingResourceGroups deletes resource groups left behind by earlier e2e runs before
+// this run starts provisioning. Failures here are logged, not fatal: a flaky reap shouldn't
+// block the current run from proceeding. Anything held in the active-locks table is left
+// alone, since that means some other concurrent run still owns it.
+func reapDanglingResourceGroups(ctx context.Context, provider cloud.Provider) {
+	dangling, err := provider.ListDanglingGroups(ctx, azure.DefaultDeleteDanglingResourcesAfter)
+	if err != nil {
+		log.Printf("Error while listing dangling resource groups: %s\n", err)
+		return
+	}
+
+	locks, err := azure.NewActiveLocksTable(cfg.Location).ActiveLocks(ctx)
+	if err != nil {
+		log.Printf("Error while reading active-locks table, skipping reap this run: %s\n", err)
+		return
+	}
+
+	for _, rg := range dangling {
+		if rg == cfg.SoakClusterName || locks[rg] {
+			continue
 		}
-	}()
+		if err := provider.DeleteResourceGroup(ctx, rg, false); err != nil {
+			log.Printf("Error while reaping resource group %s: %s\n", rg, err)
+		}
+	}
+}
+
+// tagCreatedResourceGroups stamps every resource group this run created with the standard
+// aks-engine-e2e/created/hostname tags (see azure.StandardE2ETags), so the Reaper can find and
+// clean it up even if this run is killed before it reaches teardown. It also claims each group
+// in the active-locks table, so a concurrent run's reap pass leaves them alone until this run
+// releases them in teardown.
+func tagCreatedResourceGroups(ctx context.Context, provider cloud.Provider, rgs []string) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	tags := azure.StandardE2ETags(runID(), hostname, time.Now())
+	locks := azure.NewActiveLocksTable(cfg.Location)
+	for _, rg := range rgs {
+		if err := provider.CreateResourceGroup(ctx, rg, cfg.Location, tags); err != nil {
+			log.Printf("Error while tagging resource group %s: %s\n", rg, err)
+		}
+		if err := locks.Claim(ctx, rg); err != nil {
+			log.Printf("Error while claiming active lock for resource group %s: %s\n", rg, err)
+		}
+	}
 }
 
-func teardown() {
+// runID identifies this run for the aks-engine-e2e tag: cfg.Name when the run was given one
+// up front, otherwise a timestamp-based name for a cluster the provisioner names itself.
+func runID() string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return fmt.Sprintf("e2e-%d", time.Now().Unix())
+}
+
+// buildMetricsExporters translates cfg.MetricsSinks into the concrete Exporter for each
+// configured backend, so a single run can push timings to a file, a Pushgateway, and a JSON
+// webhook at once instead of picking exactly one.
+func buildMetricsExporters() []metrics.Exporter {
+	exporters := make([]metrics.Exporter, 0, len(cfg.MetricsSinks))
+	for _, sink := range cfg.MetricsSinks {
+		switch sink.Type {
+		case "pushgateway":
+			exporters = append(exporters, &metrics.PushgatewayExporter{URL: sink.Destination})
+		case "webhook":
+			exporters = append(exporters, &metrics.WebhookExporter{Endpoint: sink.Destination})
+		default:
+			exporters = append(exporters, &metrics.FileExporter{})
+		}
+	}
+	if len(exporters) == 0 {
+		exporters = append(exporters, &metrics.FileExporter{})
+	}
+	return exporters
+}
+
+// bundleAndUploadLogs zips logsPath, the _output apimodel/kube artifacts, and a manifest into
+// a single archive and uploads it to cfg.LogSink, printing the stable
+// AKS_ENGINE_E2E_LOGS_URL=... line CI systems parse to link to the result.
+func bundleAndUploadLogs(ctx context.Context, logsPath string) {
+	uploader, err := runner.NewUploader(runner.LogSink(cfg.LogSink), cfg.LogSinkDestination)
+	if err != nil {
+		log.Printf("Error while building log uploader: %s\n", err)
+		return
+	}
+	bundler := &runner.LogBundler{
+		Name:                cfg.Name,
+		SourceDirs:          []string{logsPath, filepath.Join(cfg.CurrentWorkingDir, "_output")},
+		ClusterName:         cfg.Name,
+		Orchestrator:        cfg.Orchestrator,
+		OrchestratorVersion: cfg.OrchestratorRelease,
+		ResourceGroups:      rgs,
+		AKSEngineSHA:        cfg.GitSHA,
+		Uploader:            uploader,
+	}
+	url, err := bundler.Bundle(ctx, cfg.CurrentWorkingDir, time.Now())
+	if err != nil {
+		log.Printf("Error while bundling and uploading logs: %s\n", err)
+		return
+	}
+	fmt.Printf("AKS_ENGINE_E2E_LOGS_URL=%s\n", url)
+}
+
+// teardown runs cleanup exactly once, however many call sites race to invoke it: a failed
+// provisioning step, the ginkgo runner, the normal end-of-run path, and the signal-driven
+// shutdown goroutine in main can all reach it concurrently.
+func teardown(ctx context.Context) {
+	teardownOnce.Do(func() { doTeardown(ctx) })
+}
+
+func doTeardown(ctx context.Context) {
 	pt.RecordTotalTime()
-	pt.Write()
+	if errs := metrics.ExportAll(ctx, pt, buildMetricsExporters()); len(errs) > 0 {
+		for _, err := range errs {
+			log.Printf("Error while exporting metrics: %s\n", err)
+		}
+	}
 	hostname := fmt.Sprintf("%s.%s.cloudapp.azure.com", cfg.Name, cfg.Location)
 	logsPath := filepath.Join(cfg.CurrentWorkingDir, "_logs", hostname)
 	err := os.MkdirAll(logsPath, 0755)
@@ -233,6 +400,7 @@ func teardown() {
 		if err := cliProvisioner.FetchActivityLog(acct, logsPath); err != nil {
 			log.Printf("cannot fetch the activity log: %v", err)
 		}
+		bundleAndUploadLogs(ctx, logsPath)
 	}
 	if !cfg.RetainSSH {
 		creds := filepath.Join(cfg.CurrentWorkingDir, "_output/", "*ssh*")
@@ -247,10 +415,17 @@ func teardown() {
 			}
 		}
 	}
+	locks := azure.NewActiveLocksTable(cfg.Location)
+	for _, rg := range rgs {
+		if err := locks.Release(ctx, rg); err != nil {
+			log.Printf("Error while releasing active lock for resource group %s: %s\n", rg, err)
+		}
+	}
+
 	if cfg.CleanUpOnExit {
 		for _, rg := range rgs {
 			log.Printf("Deleting Group:%s\n", rg)
-			acct.DeleteGroupWithRetry(rg, false, 3*time.Second, cfg.Timeout)
+			acct.DeleteGroupWithRetryContext(ctx, rg, false, 3*time.Second, cfg.Timeout)
 		}
 	}
 }
@@ -0,0 +1,115 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/aks-engine/test/e2e/engine"
+)
+
+// Capability names a predicate on engine.Engine that an e2e spec can require before it runs.
+// These constants form the capability matrix GinkgoSkipFocusArgs translates into the Ginkgo
+// --skip expression handed to BuildGinkgoRunner; specs register against these names rather
+// than hand-rolling addon checks in each spec file.
+type Capability string
+
+const (
+	CapabilityDashboard          Capability = "dashboard"
+	CapabilityTiller             Capability = "tiller"
+	CapabilityACIConnector       Capability = "aci-connector"
+	CapabilityKeyvaultFlexvolume Capability = "keyvault-flexvolume"
+	CapabilityAzurePolicy        Capability = "azure-policy"
+	CapabilityAzureDiskCSI       Capability = "azuredisk-csi"
+	CapabilityAzureFileCSI       Capability = "azurefile-csi"
+	CapabilityCalico             Capability = "calico"
+	CapabilityWindowsAgents      Capability = "windows-agents"
+	CapabilityLinuxAgents        Capability = "linux-agents"
+	CapabilityGPUNode            Capability = "gpu-node"
+	CapabilityAvailabilityZones  Capability = "availability-zones"
+	CapabilityManagedIdentity    Capability = "managed-identity"
+)
+
+// capabilityPredicates wires each Capability to the engine.Engine method that decides whether
+// the current cluster definition satisfies it.
+var capabilityPredicates = map[Capability]func(*engine.Engine) bool{
+	CapabilityDashboard:          (*engine.Engine).HasDashboard,
+	CapabilityTiller:             (*engine.Engine).HasTiller,
+	CapabilityACIConnector:       (*engine.Engine).HasACIConnector,
+	CapabilityKeyvaultFlexvolume: (*engine.Engine).HasKeyvaultFlexvolume,
+	CapabilityAzurePolicy:        (*engine.Engine).HasAzurePolicy,
+	CapabilityAzureDiskCSI:       (*engine.Engine).HasAzureDiskCSI,
+	CapabilityAzureFileCSI:       (*engine.Engine).HasAzureFileCSI,
+	CapabilityCalico:             (*engine.Engine).HasCalico,
+	CapabilityWindowsAgents:      (*engine.Engine).HasWindowsAgents,
+	CapabilityLinuxAgents:        (*engine.Engine).HasLinuxAgents,
+	CapabilityGPUNode:            (*engine.Engine).HasGPUNode,
+	CapabilityAvailabilityZones:  (*engine.Engine).HasAvailabilityZones,
+	CapabilityManagedIdentity:    (*engine.Engine).HasManagedIdentity,
+}
+
+// specRegistry maps a Ginkgo spec label (the string passed to ginkgo.Describe/It) to the
+// capabilities it requires. Specs register themselves here instead of skipping ad-hoc inside
+// the spec body, so unsupported specs are skipped via --skip rather than silently passing.
+// The JUnit output shows them as skipped without a reason; the "requires X" reason is only
+// available from SkipReasons.
+var specRegistry = map[string][]Capability{}
+
+// RegisterSpec declares that the spec labeled name requires the given capabilities. Call this
+// from the spec file's init or top-level var block.
+func RegisterSpec(name string, capabilities ...Capability) {
+	specRegistry[name] = capabilities
+}
+
+// UnsupportedSpecs returns, for every registered spec whose capabilities aren't all satisfied
+// by e, the spec name and the capability that disqualified it.
+func UnsupportedSpecs(e *engine.Engine) map[string]Capability {
+	unsupported := map[string]Capability{}
+	for name, caps := range specRegistry {
+		for _, c := range caps {
+			pred, ok := capabilityPredicates[c]
+			if !ok || !pred(e) {
+				unsupported[name] = c
+				break
+			}
+		}
+	}
+	return unsupported
+}
+
+// GinkgoSkipFocusArgs translates the capability matrix for e into the --skip/--focus label
+// expressions BuildGinkgoRunner passes to the ginkgo binary, so the same test binary can drive
+// different apimodel JSONs without forking the spec tree. Only --skip is produced; focus is
+// always returned empty. The skip alternation matches the bare
+// spec name exactly as registered via RegisterSpec, since that's the literal text ginkgo matches
+// against each spec's description — it never contains a "(skipped: requires X)" suffix. Use
+// SkipReasons to get that reason for display (e.g. JUnit annotations) without corrupting the
+// match expression with it.
+func GinkgoSkipFocusArgs(e *engine.Engine) (skip string, focus string) {
+	unsupported := UnsupportedSpecs(e)
+	if len(unsupported) == 0 {
+		return "", ""
+	}
+	names := make([]string, 0, len(unsupported))
+	for name := range unsupported {
+		// QuoteMeta: spec names can contain parens, dots, and other regex metacharacters (e.g.
+		// "Dashboard (v2.0)"), which would otherwise corrupt the --skip alternation below.
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	return strings.Join(names, "|"), ""
+}
+
+// SkipReasons returns, for every spec GinkgoSkipFocusArgs will skip, a human-readable reason
+// ("requires X") for the JUnit annotation layer or console output — kept separate from the
+// --skip match expression itself, which must match only the spec's real description.
+func SkipReasons(e *engine.Engine) map[string]string {
+	unsupported := UnsupportedSpecs(e)
+	reasons := make(map[string]string, len(unsupported))
+	for name, required := range unsupported {
+		reasons[name] = fmt.Sprintf("requires %s", required)
+	}
+	return reasons
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package runner
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/Azure/aks-engine/pkg/api"
+	"github.com/Azure/aks-engine/test/e2e/engine"
+)
+
+func TestUnsupportedSpecsSkipsOnMissingCapability(t *testing.T) {
+	e := &engine.Engine{
+		ClusterDefinition: &api.ContainerService{
+			Properties: &api.Properties{
+				OrchestratorProfile: &api.OrchestratorProfile{
+					KubernetesConfig: &api.KubernetesConfig{},
+				},
+			},
+		},
+	}
+
+	specRegistry = map[string][]Capability{
+		"dashboard should render": {CapabilityDashboard},
+	}
+	defer func() { specRegistry = map[string][]Capability{} }()
+
+	unsupported := UnsupportedSpecs(e)
+	if got, ok := unsupported["dashboard should render"]; !ok || got != CapabilityDashboard {
+		t.Fatalf("expected dashboard spec to be reported as unsupported, got %v", unsupported)
+	}
+}
+
+func TestGinkgoSkipFocusArgsEmptyWhenAllSupported(t *testing.T) {
+	e := &engine.Engine{
+		ClusterDefinition: &api.ContainerService{
+			Properties: &api.Properties{
+				OrchestratorProfile: &api.OrchestratorProfile{
+					KubernetesConfig: &api.KubernetesConfig{},
+				},
+			},
+		},
+	}
+
+	specRegistry = map[string][]Capability{}
+	defer func() { specRegistry = map[string][]Capability{} }()
+
+	skip, focus := GinkgoSkipFocusArgs(e)
+	if skip != "" || focus != "" {
+		t.Fatalf("expected empty skip/focus with no registered specs, got skip=%q focus=%q", skip, focus)
+	}
+}
+
+func TestGinkgoSkipFocusArgsMatchesRealSpecName(t *testing.T) {
+	e := &engine.Engine{
+		ClusterDefinition: &api.ContainerService{
+			Properties: &api.Properties{
+				OrchestratorProfile: &api.OrchestratorProfile{
+					KubernetesConfig: &api.KubernetesConfig{},
+				},
+			},
+		},
+	}
+
+	const specName = "dashboard should render (v2.0)"
+	specRegistry = map[string][]Capability{
+		specName: {CapabilityDashboard},
+	}
+	defer func() { specRegistry = map[string][]Capability{} }()
+
+	skip, _ := GinkgoSkipFocusArgs(e)
+	if skip == "" {
+		t.Fatal("expected a non-empty --skip expression for an unsupported spec")
+	}
+	re, err := regexp.Compile(skip)
+	if err != nil {
+		t.Fatalf("--skip value is not a valid regexp: %s", err)
+	}
+	// This is the actual text ginkgo matches against: the spec's own description, with no
+	// "(skipped: requires X)" annotation baked in.
+	if !re.MatchString(specName) {
+		t.Fatalf("expected --skip %q to match the real spec name %q", skip, specName)
+	}
+
+	reasons := SkipReasons(e)
+	if reasons[specName] != "requires dashboard" {
+		t.Fatalf("expected SkipReasons to report the reason separately, got %v", reasons)
+	}
+}
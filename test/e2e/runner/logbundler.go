@@ -0,0 +1,157 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package runner
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LogSink names where LogBundler uploads the finished zip to.
+type LogSink string
+
+const (
+	// LogSinkAzureBlob uploads the bundle to an Azure Blob container. This is the default.
+	LogSinkAzureBlob LogSink = "azureblob"
+	// LogSinkS3 uploads the bundle to an S3 bucket.
+	LogSinkS3 LogSink = "s3"
+	// LogSinkLocal leaves the bundle on disk at the configured path and returns a file:// URL.
+	LogSinkLocal LogSink = "local"
+)
+
+// LogBundleManifest is written into the zip alongside the collected files so a post-mortem can
+// be automated without re-deriving the run's context from file names.
+type LogBundleManifest struct {
+	ClusterName    string            `json:"clusterName"`
+	Orchestrator   string            `json:"orchestrator"`
+	Version        string            `json:"orchestratorVersion"`
+	ResourceGroups []string          `json:"resourceGroups"`
+	AKSEngineSHA   string            `json:"aksEngineGitSHA"`
+	CreatedAt      string            `json:"createdAt"`
+	Files          map[string]string `json:"files"` // relative path -> sha256
+}
+
+// Uploader uploads a local file to a LogSink-specific destination and returns the URL it can
+// be fetched from afterward.
+type Uploader interface {
+	Upload(ctx context.Context, localPath string) (url string, err error)
+}
+
+// LogBundler collects everything teardown needs for a post-mortem, zips it, and uploads it to
+// the configured sink. It runs for every e2e run, not just soak, so a CI failure always leaves
+// a single link behind instead of requiring someone to dig through a worker's local disk.
+type LogBundler struct {
+	// Name is used as the zip's filename prefix, typically cfg.Name.
+	Name string
+	// SourceDirs are directories copied into the bundle root, e.g. _logs and _output.
+	SourceDirs []string
+	// ClusterName, Orchestrator, OrchestratorVersion, ResourceGroups, and AKSEngineSHA populate
+	// the manifest.
+	ClusterName         string
+	Orchestrator        string
+	OrchestratorVersion string
+	ResourceGroups      []string
+	AKSEngineSHA        string
+	// Uploader delivers the finished zip to the configured LogSink.
+	Uploader Uploader
+}
+
+// Bundle zips SourceDirs plus a manifest.json into outputDir/<Name>-<timestamp>.zip, uploads
+// it via Uploader, and returns the resulting URL.
+func (b *LogBundler) Bundle(ctx context.Context, outputDir string, timestamp time.Time) (string, error) {
+	zipPath := filepath.Join(outputDir, fmt.Sprintf("%s-%d.zip", b.Name, timestamp.Unix()))
+	f, err := os.Create(zipPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "creating log bundle %s", zipPath)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	manifest := LogBundleManifest{
+		ClusterName:    b.ClusterName,
+		Orchestrator:   b.Orchestrator,
+		Version:        b.OrchestratorVersion,
+		ResourceGroups: b.ResourceGroups,
+		AKSEngineSHA:   b.AKSEngineSHA,
+		CreatedAt:      timestamp.UTC().Format(time.RFC3339),
+		Files:          map[string]string{},
+	}
+
+	for _, dir := range b.SourceDirs {
+		if err := addDirToZip(zw, dir, manifest.Files); err != nil {
+			zw.Close()
+			return "", errors.Wrapf(err, "adding %s to log bundle", dir)
+		}
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		zw.Close()
+		return "", errors.Wrap(err, "marshalling log bundle manifest")
+	}
+	mw, err := zw.Create("manifest.json")
+	if err != nil {
+		zw.Close()
+		return "", errors.Wrap(err, "writing manifest.json")
+	}
+	if _, err := mw.Write(manifestBytes); err != nil {
+		zw.Close()
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", errors.Wrap(err, "finalizing log bundle zip")
+	}
+
+	if b.Uploader == nil {
+		return "file://" + zipPath, nil
+	}
+	return b.Uploader.Upload(ctx, zipPath)
+}
+
+func addDirToZip(zw *zip.Writer, dir string, shaSums map[string]string) error {
+	base := filepath.Base(dir)
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		archiveName := filepath.Join(base, rel)
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		h := sha256.New()
+		dst, err := zw.Create(archiveName)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.MultiWriter(dst, h), src); err != nil {
+			return err
+		}
+		shaSums[archiveName] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package runner
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLogBundlerBundleProducesManifestAndFiles(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "logbundler-test")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	logsDir := filepath.Join(tmp, "_logs")
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(logsDir, "cluster-info.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	b := &LogBundler{
+		Name:           "my-cluster",
+		SourceDirs:     []string{logsDir},
+		ClusterName:    "my-cluster",
+		Orchestrator:   "kubernetes",
+		ResourceGroups: []string{"my-cluster-rg"},
+		Uploader:       NewLocalUploader(filepath.Join(tmp, "dest")),
+	}
+
+	url, err := b.Bundle(context.Background(), tmp, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatalf("Bundle: %s", err)
+	}
+	if url == "" {
+		t.Fatal("expected a non-empty upload URL")
+	}
+
+	zipPath := filepath.Join(tmp, "dest", "my-cluster-1700000000.zip")
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("opening bundled zip: %s", err)
+	}
+	defer zr.Close()
+
+	var manifest LogBundleManifest
+	foundManifest := false
+	foundLogFile := false
+	for _, f := range zr.File {
+		if f.Name == "manifest.json" {
+			foundManifest = true
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("opening manifest.json: %s", err)
+			}
+			if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+				t.Fatalf("decoding manifest.json: %s", err)
+			}
+			rc.Close()
+		}
+		if filepath.Base(f.Name) == "cluster-info.txt" {
+			foundLogFile = true
+		}
+	}
+	if !foundManifest {
+		t.Fatal("expected manifest.json in bundle")
+	}
+	if !foundLogFile {
+		t.Fatal("expected _logs/cluster-info.txt in bundle")
+	}
+	if manifest.ClusterName != "my-cluster" {
+		t.Fatalf("expected manifest clusterName my-cluster, got %s", manifest.ClusterName)
+	}
+	if len(manifest.Files) != 1 {
+		t.Fatalf("expected 1 file sha entry, got %d", len(manifest.Files))
+	}
+}
+
+func TestNewUploaderUnknownSink(t *testing.T) {
+	if _, err := NewUploader(LogSink("carrier-pigeon"), "dest"); err == nil {
+		t.Fatal("expected an error for an unknown log sink")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// localUploader copies the bundle into destDir and returns a file:// URL. It backs
+// LogSinkLocal and is also useful for running the harness against a laptop disk in dev.
+type localUploader struct {
+	destDir string
+}
+
+// NewLocalUploader returns an Uploader that copies bundles into destDir.
+func NewLocalUploader(destDir string) Uploader {
+	return &localUploader{destDir: destDir}
+}
+
+func (u *localUploader) Upload(ctx context.Context, localPath string) (string, error) {
+	if err := os.MkdirAll(u.destDir, 0755); err != nil {
+		return "", errors.Wrapf(err, "creating local log sink directory %s", u.destDir)
+	}
+	dest := filepath.Join(u.destDir, filepath.Base(localPath))
+	src, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	dst, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", err
+	}
+	return "file://" + dest, nil
+}
+
+// blobUploader uploads the bundle to an Azure Blob container. It is the default LogSink.
+type blobUploader struct {
+	containerURL string // e.g. https://<account>.blob.core.windows.net/<container>?<sas>
+}
+
+// NewBlobUploader returns an Uploader targeting the given Azure Blob container SAS URL.
+func NewBlobUploader(containerURL string) Uploader {
+	return &blobUploader{containerURL: containerURL}
+}
+
+func (u *blobUploader) Upload(ctx context.Context, localPath string) (string, error) {
+	blobURL, err := u.blobURL(localPath)
+	if err != nil {
+		return "", errors.Wrap(err, "building blob URL")
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, blobURL, f)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", "2020-04-08")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "uploading blob")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", errors.Errorf("unexpected status uploading blob: %s", resp.Status)
+	}
+
+	// Return blobURL as-is, SAS query string and all: the container needed a SAS to write this
+	// blob, so it almost certainly needs one to read it back too. Stripping the query here would
+	// leave AKS_ENGINE_E2E_LOGS_URL pointing at a blob that 403s for anyone without independent
+	// access to the storage account.
+	return blobURL, nil
+}
+
+// blobURL inserts the bundle's blob name ahead of the container SAS query string, e.g.
+// https://acct.blob.core.windows.net/container?sv=... -> .../container/bundle.zip?sv=...
+func (u *blobUploader) blobURL(localPath string) (string, error) {
+	parsed, err := url.Parse(u.containerURL)
+	if err != nil {
+		return "", err
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/") + "/" + filepath.Base(localPath)
+	return parsed.String(), nil
+}
+
+// s3Uploader uploads the bundle to an S3 bucket.
+type s3Uploader struct {
+	bucket string
+	prefix string
+}
+
+// NewS3Uploader returns an Uploader targeting the given S3 bucket and key prefix.
+func NewS3Uploader(bucket, prefix string) Uploader {
+	return &s3Uploader{bucket: bucket, prefix: prefix}
+}
+
+func (u *s3Uploader) Upload(ctx context.Context, localPath string) (string, error) {
+	key := filepath.Join(u.prefix, filepath.Base(localPath))
+	dest := fmt.Sprintf("s3://%s/%s", u.bucket, key)
+
+	out, err := exec.CommandContext(ctx, "aws", "s3", "cp", localPath, dest).CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "aws s3 cp: %s", strings.TrimSpace(string(out)))
+	}
+	return dest, nil
+}
+
+// NewUploader builds the Uploader for the given sink and destination, so callers only need to
+// know cfg.LogSink rather than every sink's constructor.
+func NewUploader(sink LogSink, destination string) (Uploader, error) {
+	switch sink {
+	case LogSinkAzureBlob, "":
+		return NewBlobUploader(destination), nil
+	case LogSinkS3:
+		return NewS3Uploader(destination, ""), nil
+	case LogSinkLocal:
+		return NewLocalUploader(destination), nil
+	default:
+		return nil, errors.Errorf("unknown log sink %q", sink)
+	}
+}